@@ -0,0 +1,202 @@
+package api
+
+import (
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/apis/rbac"
+)
+
+func convertPolicyRulesToRBAC(in []PolicyRule) []rbac.PolicyRule {
+	out := make([]rbac.PolicyRule, len(in))
+	for i := range in {
+		out[i] = rbac.PolicyRule{
+			Verbs:           in[i].Verbs,
+			APIGroups:       in[i].APIGroups,
+			Resources:       in[i].Resources,
+			ResourceNames:   in[i].ResourceNames,
+			NonResourceURLs: in[i].NonResourceURLs,
+		}
+	}
+	return out
+}
+
+func convertPolicyRulesFromRBAC(in []rbac.PolicyRule) []PolicyRule {
+	out := make([]PolicyRule, len(in))
+	for i := range in {
+		out[i] = PolicyRule{
+			Verbs:           in[i].Verbs,
+			APIGroups:       in[i].APIGroups,
+			Resources:       in[i].Resources,
+			ResourceNames:   in[i].ResourceNames,
+			NonResourceURLs: in[i].NonResourceURLs,
+		}
+	}
+	return out
+}
+
+func convertSubjectsToRBAC(in []Subject) []rbac.Subject {
+	out := make([]rbac.Subject, len(in))
+	for i := range in {
+		out[i] = rbac.Subject{
+			Kind:      in[i].Kind,
+			Namespace: in[i].Namespace,
+			Name:      in[i].Name,
+		}
+	}
+	return out
+}
+
+func convertSubjectsFromRBAC(in []rbac.Subject) []Subject {
+	out := make([]Subject, len(in))
+	for i := range in {
+		out[i] = Subject{
+			Kind:      in[i].Kind,
+			Namespace: in[i].Namespace,
+			Name:      in[i].Name,
+		}
+	}
+	return out
+}
+
+// convertRoleRefToRBAC carries in.Kind through to the RBAC side, falling back
+// to defaultKind only for a RoleRef written before Kind existed: every
+// RoleBinding predating this field referenced a same-namespace Role, and
+// every ClusterRoleBinding a ClusterRole, so the zero value is unambiguous.
+func convertRoleRefToRBAC(in RoleRef, defaultKind string) rbac.RoleRef {
+	kind := in.Kind
+	if kind == "" {
+		kind = defaultKind
+	}
+	return rbac.RoleRef{
+		APIGroup: rbac.GroupName,
+		Kind:     kind,
+		Name:     in.Name,
+	}
+}
+
+func convertRoleRefFromRBAC(in rbac.RoleRef) RoleRef {
+	return RoleRef{Kind: in.Kind, Name: in.Name}
+}
+
+func Convert_api_Role_To_rbac_Role(in *Role, out *rbac.Role, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	out.Rules = convertPolicyRulesToRBAC(in.Rules)
+	return nil
+}
+
+func Convert_rbac_Role_To_api_Role(in *rbac.Role, out *Role, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	out.Rules = convertPolicyRulesFromRBAC(in.Rules)
+	return nil
+}
+
+func Convert_api_ClusterRole_To_rbac_ClusterRole(in *ClusterRole, out *rbac.ClusterRole, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	out.Rules = convertPolicyRulesToRBAC(in.Rules)
+	return nil
+}
+
+func Convert_rbac_ClusterRole_To_api_ClusterRole(in *rbac.ClusterRole, out *ClusterRole, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	out.Rules = convertPolicyRulesFromRBAC(in.Rules)
+	return nil
+}
+
+func Convert_api_RoleBinding_To_rbac_RoleBinding(in *RoleBinding, out *rbac.RoleBinding, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	out.Subjects = convertSubjectsToRBAC(in.Subjects)
+	out.RoleRef = convertRoleRefToRBAC(in.RoleRef, "Role")
+	return nil
+}
+
+func Convert_rbac_RoleBinding_To_api_RoleBinding(in *rbac.RoleBinding, out *RoleBinding, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	out.Subjects = convertSubjectsFromRBAC(in.Subjects)
+	out.RoleRef = convertRoleRefFromRBAC(in.RoleRef)
+	return nil
+}
+
+func Convert_api_ClusterRoleBinding_To_rbac_ClusterRoleBinding(in *ClusterRoleBinding, out *rbac.ClusterRoleBinding, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	out.Subjects = convertSubjectsToRBAC(in.Subjects)
+	out.RoleRef = convertRoleRefToRBAC(in.RoleRef, "ClusterRole")
+	return nil
+}
+
+func Convert_rbac_ClusterRoleBinding_To_api_ClusterRoleBinding(in *rbac.ClusterRoleBinding, out *ClusterRoleBinding, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	out.Subjects = convertSubjectsFromRBAC(in.Subjects)
+	out.RoleRef = convertRoleRefFromRBAC(in.RoleRef)
+	return nil
+}
+
+// DeepCopy_api_Role, DeepCopy_api_RoleBinding, DeepCopy_api_ClusterRole, and
+// DeepCopy_api_ClusterRoleBinding follow the same generated-deepcopy
+// signature as their rbac package counterparts (e.g. rbac.DeepCopy_rbac_Role)
+// so that callers can use them identically on either side of a conversion.
+// Unlike the generated functions, they allocate only a new top-level object
+// and a new Rules or Subjects slice: the ObjectMeta maps and slices, and the
+// slices inside each PolicyRule, stay shared with in.
+
+func DeepCopy_api_Role(in *Role, out *Role, c *conversion.Cloner) error {
+	*out = *in
+	out.Rules = append([]PolicyRule(nil), in.Rules...)
+	return nil
+}
+
+func DeepCopy_api_RoleBinding(in *RoleBinding, out *RoleBinding, c *conversion.Cloner) error {
+	*out = *in
+	out.Subjects = append([]Subject(nil), in.Subjects...)
+	return nil
+}
+
+func DeepCopy_api_ClusterRole(in *ClusterRole, out *ClusterRole, c *conversion.Cloner) error {
+	*out = *in
+	out.Rules = append([]PolicyRule(nil), in.Rules...)
+	return nil
+}
+
+func DeepCopy_api_ClusterRoleBinding(in *ClusterRoleBinding, out *ClusterRoleBinding, c *conversion.Cloner) error {
+	*out = *in
+	out.Subjects = append([]Subject(nil), in.Subjects...)
+	return nil
+}
+
+// DeepCopyObject implementations below satisfy runtime.Object so that these
+// types can be handed to an EventRecorder.  GetObjectKind is already
+// satisfied through the embedded metav1.TypeMeta.
+
+func (in *Role) DeepCopyObject() runtime.Object {
+	out := &Role{}
+	DeepCopy_api_Role(in, out, conversion.NewCloner())
+	return out
+}
+
+func (in *RoleBinding) DeepCopyObject() runtime.Object {
+	out := &RoleBinding{}
+	DeepCopy_api_RoleBinding(in, out, conversion.NewCloner())
+	return out
+}
+
+func (in *ClusterRole) DeepCopyObject() runtime.Object {
+	out := &ClusterRole{}
+	DeepCopy_api_ClusterRole(in, out, conversion.NewCloner())
+	return out
+}
+
+func (in *ClusterRoleBinding) DeepCopyObject() runtime.Object {
+	out := &ClusterRoleBinding{}
+	DeepCopy_api_ClusterRoleBinding(in, out, conversion.NewCloner())
+	return out
+}
+
+func (in *Policy) DeepCopyObject() runtime.Object {
+	out := &Policy{ObjectMeta: in.ObjectMeta, TypeMeta: in.TypeMeta}
+	if in.Roles != nil {
+		out.Roles = make(map[string]*Role, len(in.Roles))
+		for k, v := range in.Roles {
+			out.Roles[k] = v.DeepCopyObject().(*Role)
+		}
+	}
+	return out
+}
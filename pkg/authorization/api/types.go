@@ -0,0 +1,108 @@
+package api
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PolicyRule holds information that describes a policy rule, but does not
+// have information about who the rule applies to or which namespace the
+// rule applies to.  This predates RBAC in origin's authorization API and is
+// kept so that objects stored before the RBAC migration keep working.
+type PolicyRule struct {
+	Verbs           []string
+	APIGroups       []string
+	Resources       []string
+	ResourceNames   []string
+	NonResourceURLs []string
+}
+
+// RoleRef contains information that points to the role being used.  Kind is
+// "Role" or "ClusterRole"; it is empty on objects written before this field
+// existed, in which case callers fall back to the only kind that binding
+// type could have referenced.
+type RoleRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// Subject contains a reference to the object or user identities a role
+// binding applies to.
+type Subject struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// Role is a logical grouping of PolicyRules that can be referenced as a
+// unit by RoleBindings.
+type Role struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	Rules []PolicyRule
+}
+
+// RoleBinding references a Role, but not contain it; it can reference a Role
+// in the same namespace or a ClusterRole, as RoleRef.Kind says.
+type RoleBinding struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	Subjects []Subject
+	RoleRef  RoleRef
+}
+
+// ClusterRole is a logical grouping of PolicyRules that can be referenced as
+// a unit by ClusterRoleBindings, at cluster scope.
+type ClusterRole struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	Rules []PolicyRule
+}
+
+// ClusterRoleBinding references a ClusterRole, but not contain it, at
+// cluster scope.
+type ClusterRoleBinding struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	Subjects []Subject
+	RoleRef  RoleRef
+}
+
+// Policy is a object that holds all the Roles for a particular namespace. It
+// is always named "default".
+type Policy struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	Roles map[string]*Role
+}
+
+// PolicyBinding is a object that holds all the RoleBindings for a particular
+// namespace, sourced from a single Policy's namespace.
+type PolicyBinding struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	RoleBindings map[string]*RoleBinding
+}
+
+// ClusterPolicy is the cluster-scoped equivalent of Policy. It is always
+// named "default".
+type ClusterPolicy struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	Roles map[string]*ClusterRole
+}
+
+// ClusterPolicyBinding is the cluster-scoped equivalent of PolicyBinding.
+type ClusterPolicyBinding struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	RoleBindings map[string]*ClusterRoleBinding
+}
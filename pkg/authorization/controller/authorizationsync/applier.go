@@ -0,0 +1,174 @@
+package authorizationsync
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/glog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/client-go/tools/record"
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/apis/rbac"
+	rbacclient "k8s.io/kubernetes/pkg/client/clientset_generated/internalclientset/typed/rbac/internalversion"
+)
+
+// RoleApplyResult describes the effect an Applier had, or in dry-run mode
+// would have had, on the RBAC side of a Role sync.
+type RoleApplyResult string
+
+const (
+	RoleApplyResultCreated RoleApplyResult = "created"
+	RoleApplyResultUpdated RoleApplyResult = "updated"
+	RoleApplyResultDeleted RoleApplyResult = "deleted"
+
+	// RoleApplyResultWouldCreate, RoleApplyResultWouldUpdate, and
+	// RoleApplyResultWouldDelete are DryRunRoleApplier's equivalents of the
+	// results above.  They get their own values, rather than reusing
+	// RoleApplyResultCreated/Updated/Deleted, so that a syncsTotal metric
+	// sample or a recorded Event can never be mistaken for a real write.
+	RoleApplyResultWouldCreate RoleApplyResult = "would-create"
+	RoleApplyResultWouldUpdate RoleApplyResult = "would-update"
+	RoleApplyResultWouldDelete RoleApplyResult = "would-delete"
+)
+
+// RoleApplier reconciles the RBAC side of a Role sync.  syncRole calls Apply
+// once it has already decided that desired and existing differ, and calls
+// Delete once it has decided the RBAC role should no longer exist.
+//
+// Only OriginRoleToRBACRoleController is built on top of RoleApplier today.
+// The ClusterRole/ClusterRoleBinding controllers and the four reverse
+// RBAC->origin controllers still write through their clients directly; a
+// ClusterRoleApplier (and an origin-side equivalent for the reverse
+// direction) would need its own interface per object kind, which is left
+// for a follow-up rather than folded into this change.
+type RoleApplier interface {
+	// Apply makes existing match desired.  existing is nil if no RBAC role
+	// has been observed yet, in which case Apply creates one.
+	Apply(namespace string, desired, existing *rbac.Role) (RoleApplyResult, error)
+	// Delete removes the named RBAC role.
+	Delete(namespace, name string) (RoleApplyResult, error)
+}
+
+// SyncMode selects which RoleApplier a sync controller is built with.  There
+// is no --authorization-sync-mode flag yet; callers select a SyncMode in
+// code until this package is wired into a cmd/ binary.
+type SyncMode string
+
+const (
+	// SyncModeLive performs the sync: creates, updates, and deletes the
+	// RBAC object directly.  This is the default.
+	SyncModeLive SyncMode = "live"
+	// SyncModeDryRun computes and logs what would change, and records it as
+	// Events, without calling the RBAC API.
+	SyncModeDryRun SyncMode = "dryrun"
+	// SyncModeSSA is reserved for ServerSideApplyRoleApplier.  NewRoleApplier
+	// refuses to build it today: see the ServerSideApplyRoleApplier doc
+	// comment for why it isn't functional yet.
+	SyncModeSSA SyncMode = "ssa"
+)
+
+// NewRoleApplier builds the RoleApplier selected by mode.
+func NewRoleApplier(mode SyncMode, rbacClient rbacclient.RolesGetter, eventRecorder record.EventRecorder) (RoleApplier, error) {
+	switch mode {
+	case SyncModeLive, "":
+		return &LiveRoleApplier{rbacClient: rbacClient}, nil
+	case SyncModeDryRun:
+		return &DryRunRoleApplier{eventRecorder: eventRecorder}, nil
+	case SyncModeSSA:
+		return nil, fmt.Errorf("authorization sync mode %q is not functional yet: the internalversion RBAC client this package is built on has no field-manager-aware Patch, so a Server-Side Apply write can't be attributed to a field manager; see ServerSideApplyRoleApplier", mode)
+	default:
+		return nil, fmt.Errorf("unrecognized authorization sync mode %q", mode)
+	}
+}
+
+// LiveRoleApplier is the original, direct behavior: it writes straight to
+// the RBAC API and falls back to deleting an object that was rejected as an
+// invalid update.
+type LiveRoleApplier struct {
+	rbacClient rbacclient.RolesGetter
+}
+
+func (a *LiveRoleApplier) Apply(namespace string, desired, existing *rbac.Role) (RoleApplyResult, error) {
+	if existing == nil {
+		desired.ResourceVersion = ""
+		_, err := a.rbacClient.Roles(namespace).Create(desired)
+		return RoleApplyResultCreated, err
+	}
+
+	glog.V(1).Infof("writing RBAC role %v/%v", namespace, desired.Name)
+	_, err := a.rbacClient.Roles(namespace).Update(desired)
+	if apierrors.IsInvalid(err) {
+		// we're probably changing an immutable field or something like that.
+		// either way, the existing object is wrong.  Delete it and try again.
+		invalidDeletesTotal.Inc()
+		a.rbacClient.Roles(namespace).Delete(desired.Name, nil)
+		return RoleApplyResultDeleted, err
+	}
+	return RoleApplyResultUpdated, err
+}
+
+func (a *LiveRoleApplier) Delete(namespace, name string) (RoleApplyResult, error) {
+	// orphan on delete to minimize fanout.  We ought to clean the rest via controller too.
+	return RoleApplyResultDeleted, a.rbacClient.Roles(namespace).Delete(name, nil)
+}
+
+// DryRunRoleApplier never calls the RBAC API.  It logs what it would have
+// done and, for an update, records the diff as an Event on the existing RBAC
+// role, so an administrator can validate a sync mode before switching to live.
+type DryRunRoleApplier struct {
+	eventRecorder record.EventRecorder
+}
+
+func (a *DryRunRoleApplier) Apply(namespace string, desired, existing *rbac.Role) (RoleApplyResult, error) {
+	if existing == nil {
+		glog.V(2).Infof("dry-run: would create RBAC role %s/%s", namespace, desired.Name)
+		return RoleApplyResultWouldCreate, nil
+	}
+
+	objectDiff := diff.ObjectDiff(existing, desired)
+	glog.V(2).Infof("dry-run: would update RBAC role %s/%s:\n%s", namespace, desired.Name, objectDiff)
+	if a.eventRecorder != nil {
+		a.eventRecorder.Eventf(existing, kapi.EventTypeNormal, "RBACRoleDryRunDiff", "would update RBAC role %s/%s:\n%s", namespace, desired.Name, objectDiff)
+	}
+	return RoleApplyResultWouldUpdate, nil
+}
+
+func (a *DryRunRoleApplier) Delete(namespace, name string) (RoleApplyResult, error) {
+	glog.V(2).Infof("dry-run: would delete RBAC role %s/%s", namespace, name)
+	return RoleApplyResultWouldDelete, nil
+}
+
+// ServerSideApplyRoleApplier is intended to use Server-Side Apply so that
+// fields a user set directly on the RBAC role (for example with kubectl
+// edit) on paths we don't manage survive future syncs, instead of being
+// stomped by a full Update the way LiveRoleApplier does it.
+//
+// It is not functional yet and NewRoleApplier refuses to build one: the
+// internalversion RoleInterface this package is built on predates
+// PatchOptions.FieldManager, so the Patch call below applies without being
+// attributed to any field manager, which defeats the point of SSA. This type
+// is kept so the apply-patch mechanics don't need to be rediscovered once a
+// field-manager-aware client is available.
+type ServerSideApplyRoleApplier struct {
+	rbacClient rbacclient.RolesGetter
+}
+
+func (a *ServerSideApplyRoleApplier) Apply(namespace string, desired, existing *rbac.Role) (RoleApplyResult, error) {
+	data, err := json.Marshal(desired)
+	if err != nil {
+		return RoleApplyResultUpdated, err
+	}
+
+	_, err = a.rbacClient.Roles(namespace).Patch(desired.Name, types.ApplyPatchType, data)
+	if existing == nil {
+		return RoleApplyResultCreated, err
+	}
+	return RoleApplyResultUpdated, err
+}
+
+func (a *ServerSideApplyRoleApplier) Delete(namespace, name string) (RoleApplyResult, error) {
+	return RoleApplyResultDeleted, a.rbacClient.Roles(namespace).Delete(name, nil)
+}
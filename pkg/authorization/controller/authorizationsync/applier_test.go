@@ -0,0 +1,122 @@
+package authorizationsync
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/kubernetes/pkg/apis/rbac"
+	fakerbacclient "k8s.io/kubernetes/pkg/client/clientset_generated/internalclientset/fake"
+)
+
+func TestLiveRoleApplierApplyCreatesWhenNoExisting(t *testing.T) {
+	client := fakerbacclient.NewSimpleClientset()
+	a := &LiveRoleApplier{rbacClient: client.Rbac()}
+
+	desired := &rbac.Role{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "edit"}}
+	result, err := a.Apply("ns", desired, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != RoleApplyResultCreated {
+		t.Errorf("expected %q, got %q", RoleApplyResultCreated, result)
+	}
+
+	if _, err := client.Rbac().Roles("ns").Get("edit", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected role to have been created: %v", err)
+	}
+}
+
+func TestLiveRoleApplierApplyUpdatesWhenExisting(t *testing.T) {
+	existing := &rbac.Role{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "edit", ResourceVersion: "1"}}
+	client := fakerbacclient.NewSimpleClientset(existing)
+	a := &LiveRoleApplier{rbacClient: client.Rbac()}
+
+	desired := existing.DeepCopy()
+	desired.Rules = []rbac.PolicyRule{{Verbs: []string{"get"}}}
+	result, err := a.Apply("ns", desired, existing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != RoleApplyResultUpdated {
+		t.Errorf("expected %q, got %q", RoleApplyResultUpdated, result)
+	}
+
+	got, err := client.Rbac().Roles("ns").Get("edit", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Rules) != 1 {
+		t.Errorf("expected the update to have been written, got %+v", got.Rules)
+	}
+}
+
+func TestLiveRoleApplierDelete(t *testing.T) {
+	existing := &rbac.Role{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "edit"}}
+	client := fakerbacclient.NewSimpleClientset(existing)
+	a := &LiveRoleApplier{rbacClient: client.Rbac()}
+
+	result, err := a.Delete("ns", "edit")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != RoleApplyResultDeleted {
+		t.Errorf("expected %q, got %q", RoleApplyResultDeleted, result)
+	}
+	if _, err := client.Rbac().Roles("ns").Get("edit", metav1.GetOptions{}); err == nil {
+		t.Error("expected role to have been deleted")
+	}
+}
+
+// TestDryRunRoleApplierNeverCallsTheAPI exercises the same Create/Update/
+// Delete scenarios as the LiveRoleApplier tests above, but asserts the
+// opposite: DryRunRoleApplier must report its own Would* results and must
+// never touch the RBAC API, so its Events and syncsTotal samples can never
+// be confused with a real sync.
+func TestDryRunRoleApplierNeverCallsTheAPI(t *testing.T) {
+	client := fakerbacclient.NewSimpleClientset(&rbac.Role{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "edit"}})
+	recorder := &record.FakeRecorder{Events: make(chan string, 10)}
+	a := &DryRunRoleApplier{eventRecorder: recorder}
+
+	existing := &rbac.Role{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "edit"}}
+	desired := existing.DeepCopy()
+	desired.Rules = []rbac.PolicyRule{{Verbs: []string{"get"}}}
+
+	createResult, err := a.Apply("ns", desired, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if createResult != RoleApplyResultWouldCreate {
+		t.Errorf("expected %q, got %q", RoleApplyResultWouldCreate, createResult)
+	}
+
+	updateResult, err := a.Apply("ns", desired, existing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updateResult != RoleApplyResultWouldUpdate {
+		t.Errorf("expected %q, got %q", RoleApplyResultWouldUpdate, updateResult)
+	}
+
+	deleteResult, err := a.Delete("ns", "edit")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deleteResult != RoleApplyResultWouldDelete {
+		t.Errorf("expected %q, got %q", RoleApplyResultWouldDelete, deleteResult)
+	}
+
+	for _, result := range []RoleApplyResult{createResult, updateResult, deleteResult} {
+		if result == RoleApplyResultCreated || result == RoleApplyResultUpdated || result == RoleApplyResultDeleted {
+			t.Errorf("dry-run result %q must not equal a live result", result)
+		}
+	}
+
+	got, err := client.Rbac().Roles("ns").Get("edit", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("dry-run must not have deleted the role: %v", err)
+	}
+	if len(got.Rules) != 0 {
+		t.Errorf("dry-run must not have written the update, got %+v", got.Rules)
+	}
+}
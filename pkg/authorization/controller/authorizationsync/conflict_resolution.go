@@ -0,0 +1,46 @@
+package authorizationsync
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ConflictResolution controls how a reverse (RBAC->Origin) sync controller
+// behaves when the origin and RBAC views of an object differ, including when
+// one side exists and the other does not.
+type ConflictResolution int
+
+const (
+	// ConflictResolutionRBACWins overwrites the origin side with the RBAC
+	// object whenever the two have diverged.  This is the default: it treats
+	// RBAC as the new source of truth during migration off origin APIs.
+	ConflictResolutionRBACWins ConflictResolution = iota
+	// ConflictResolutionOriginWins leaves the origin side untouched on
+	// divergence and lets the origin->RBAC controller stomp RBAC back to
+	// match on its next sync.
+	ConflictResolutionOriginWins
+	// ConflictResolutionReject refuses to sync a divergent pair at all and
+	// surfaces an error instead, requiring an administrator to reconcile the
+	// two sides by hand before syncing resumes.
+	ConflictResolutionReject
+)
+
+// maxConflictRetries bounds the optimistic-concurrency retry loop used when
+// merging an individual role or binding into a shared Policy/PolicyBinding
+// container object.
+const maxConflictRetries = 10
+
+// retryOnConflict calls fn until it returns a non-conflict error, succeeds, or
+// maxConflictRetries attempts have been made.  It exists because updates in
+// this package mutate one key of a container object (Policy.Roles,
+// PolicyBinding.RoleBindings) shared by every role in a namespace, so
+// concurrent syncs routinely race on the container's ResourceVersion.
+func retryOnConflict(fn func() error) error {
+	var err error
+	for i := 0; i < maxConflictRetries; i++ {
+		err = fn()
+		if err == nil || !apierrors.IsConflict(err) {
+			return err
+		}
+	}
+	return err
+}
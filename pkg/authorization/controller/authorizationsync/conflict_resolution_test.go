@@ -0,0 +1,73 @@
+package authorizationsync
+
+import (
+	"fmt"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func conflictErr() error {
+	return apierrors.NewConflict(schema.GroupResource{Resource: "policies"}, "default", fmt.Errorf("resourceVersion mismatch"))
+}
+
+func TestRetryOnConflictSucceedsFirstTry(t *testing.T) {
+	calls := 0
+	err := retryOnConflict(func() error {
+		calls++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestRetryOnConflictRetriesUntilSuccess(t *testing.T) {
+	calls := 0
+	err := retryOnConflict(func() error {
+		calls++
+		if calls < 3 {
+			return conflictErr()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRetryOnConflictStopsOnNonConflictError(t *testing.T) {
+	calls := 0
+	wantErr := fmt.Errorf("boom")
+	err := retryOnConflict(func() error {
+		calls++
+		return wantErr
+	})
+	if err != wantErr {
+		t.Fatalf("expected %v, got %v", wantErr, err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestRetryOnConflictGivesUpAfterMaxRetries(t *testing.T) {
+	calls := 0
+	err := retryOnConflict(func() error {
+		calls++
+		return conflictErr()
+	})
+	if err == nil || !apierrors.IsConflict(err) {
+		t.Fatalf("expected a conflict error, got %v", err)
+	}
+	if calls != maxConflictRetries {
+		t.Errorf("expected %d calls, got %d", maxConflictRetries, calls)
+	}
+}
@@ -0,0 +1,150 @@
+package authorizationsync
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/kubernetes/pkg/controller"
+)
+
+// cloner is shared by every sync controller in this package.  It is used after
+// a generated conversion function runs, since those functions do not guarantee
+// that a new object is allocated.
+var cloner = conversion.NewCloner()
+
+// lastSyncAnnotation is stamped onto the RBAC side of a sync pair with the
+// origin ResourceVersion that produced it, so operators can tell whether the
+// two sides have drifted since the last successful sync.
+const lastSyncAnnotation = "authorization.openshift.io/last-sync"
+
+// genericController holds the machinery that every Origin<->RBAC sync
+// controller in this package shares: a named workqueue, a cache-sync gate,
+// a single sync function invoked once per dequeued key, and an event
+// recorder used to surface create/update/delete/skip decisions.
+type genericController struct {
+	name          string
+	cachesSynced  func() bool
+	syncFunc      func(key string) error
+	queue         workqueue.RateLimitingInterface
+	eventRecorder record.EventRecorder
+}
+
+// Run starts the given number of workers and blocks until stopCh is closed.
+func (c *genericController) Run(workers int, stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	glog.Infof("Starting %s controller", c.name)
+	defer glog.Infof("Shutting down %s controller", c.name)
+
+	if !cache.WaitForCacheSync(stopCh, c.cachesSynced) {
+		return
+	}
+
+	for i := 0; i < workers; i++ {
+		go wait.Until(c.runWorker, time.Second, stopCh)
+	}
+
+	<-stopCh
+}
+
+func (c *genericController) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *genericController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.syncFunc(key.(string)); err != nil {
+		utilruntime.HandleError(fmt.Errorf("%v failed with : %v", key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
+
+// stampLastSync records originResourceVersion on rbacObject's
+// lastSyncAnnotation so operators can see, from the RBAC object alone, which
+// origin generation it was last synced from.
+func stampLastSync(rbacObject metav1.Object, originResourceVersion string) {
+	annotations := rbacObject.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[lastSyncAnnotation] = originResourceVersion
+	rbacObject.SetAnnotations(annotations)
+}
+
+// recordEvent surfaces a create/update/delete/skip decision as a Kubernetes
+// Event on object, and increments the authorizationsync_syncs_total counter
+// for result.  If no event recorder was supplied at construction time, only
+// the Event is skipped; the counter is still incremented.
+func (c *genericController) recordEvent(object runtime.Object, eventType, reason, result, messageFmt string, args ...interface{}) {
+	syncsTotal.WithLabelValues(result).Inc()
+	if c.eventRecorder == nil {
+		return
+	}
+	c.eventRecorder.Eventf(object, eventType, reason, messageFmt, args...)
+}
+
+// recordMirrorEvent surfaces the same create/update/delete/skip decision as
+// an Event on object, the counterpart on the other side of the sync pair
+// from whatever object was already passed to recordEvent for this
+// decision. It does not increment authorizationsync_syncs_total again: the
+// accompanying recordEvent call already counted this decision once.
+func (c *genericController) recordMirrorEvent(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if c.eventRecorder == nil {
+		return
+	}
+	c.eventRecorder.Eventf(object, eventType, reason, messageFmt, args...)
+}
+
+// naiveEventHandler enqueues an object's key on every add, update, and delete
+// with no filtering.  It is used on the RBAC side of each sync pair: any
+// observed change there is a candidate to be stomped back to the origin state
+// by an origin->RBAC controller, or merged into origin by an RBAC->origin one.
+func naiveEventHandler(queue workqueue.RateLimitingInterface) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			key, err := controller.KeyFunc(obj)
+			if err != nil {
+				utilruntime.HandleError(err)
+				return
+			}
+			queue.Add(key)
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			key, err := controller.KeyFunc(cur)
+			if err != nil {
+				utilruntime.HandleError(err)
+				return
+			}
+			queue.Add(key)
+		},
+		DeleteFunc: func(obj interface{}) {
+			key, err := controller.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			if err != nil {
+				utilruntime.HandleError(err)
+				return
+			}
+			queue.Add(key)
+		},
+	}
+}
@@ -0,0 +1,46 @@
+package authorizationsync
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	syncsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "authorizationsync",
+			Name:      "syncs_total",
+			Help:      "Number of origin<->RBAC authorization object syncs, by result (created, updated, deleted, rejected, none, and would-create, would-update, would-delete in dry-run mode).",
+		},
+		[]string{"result"},
+	)
+
+	conversionErrorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: "authorizationsync",
+			Name:      "conversion_errors_total",
+			Help:      "Number of times converting between an origin authorization object and its RBAC equivalent failed.",
+		},
+	)
+
+	invalidDeletesTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: "authorizationsync",
+			Name:      "invalid_deletes_total",
+			Help:      "Number of times an update was rejected as invalid and the sync controller fell back to deleting the existing object.",
+		},
+	)
+
+	registerMetrics sync.Once
+)
+
+// RegisterMetrics registers the authorizationsync collectors with the legacy
+// Prometheus registry.  It is safe to call more than once.
+func RegisterMetrics() {
+	registerMetrics.Do(func() {
+		prometheus.MustRegister(syncsTotal)
+		prometheus.MustRegister(conversionErrorsTotal)
+		prometheus.MustRegister(invalidDeletesTotal)
+	})
+}
@@ -0,0 +1,92 @@
+package authorizationsync
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corelisters "k8s.io/kubernetes/pkg/client/listers/core/internalversion"
+)
+
+// syncDisabledAnnotation opts a single origin or RBAC object out of sync: a
+// syncRole-style function that sees it on either side of a pair leaves both
+// sides untouched.
+const syncDisabledAnnotation = "authorization.openshift.io/sync"
+
+// isSyncDisabled reports whether annotations carries the per-object sync
+// opt-out.
+func isSyncDisabled(annotations map[string]string) bool {
+	return annotations[syncDisabledAnnotation] == "false"
+}
+
+// Option configures the namespace scoping of a sync controller's
+// constructor.  Namespaced controllers accept zero or more Options.
+type Option func(*namespaceFilter)
+
+// WithNamespaceSelector restricts syncing to namespaces whose labels match
+// selector, resolving namespace labels through namespaceLister.
+func WithNamespaceSelector(selector labels.Selector, namespaceLister corelisters.NamespaceLister) Option {
+	return func(f *namespaceFilter) {
+		f.selector = selector
+		f.namespaceLister = namespaceLister
+	}
+}
+
+// WithNamespaces restricts syncing to exactly the given namespaces.
+func WithNamespaces(namespaces []string) Option {
+	return func(f *namespaceFilter) {
+		f.included = sets.NewString(namespaces...)
+	}
+}
+
+// WithExcludedNamespaces opts the given namespaces out of syncing, even if
+// they would otherwise match WithNamespaces or WithNamespaceSelector.
+func WithExcludedNamespaces(namespaces []string) Option {
+	return func(f *namespaceFilter) {
+		f.excluded = sets.NewString(namespaces...)
+	}
+}
+
+// namespaceFilter decides whether a namespace is in scope for a sync
+// controller.  The zero value matches every namespace.
+//
+// Matches is applied post-hoc, in a container event handler and again in the
+// sync function for keys enqueued from the RBAC side, rather than
+// pushed into a ListOptions/FieldSelector on the originating informer: every
+// constructor in this package takes its RoleInformer/PolicyInformer (etc.)
+// already built, off a SharedInformerFactory whose whole point is that one
+// watch and one cache serve every consumer of that resource type. Giving a
+// single controller a scoped field selector would mean it could no longer
+// share that informer, so it would need its own unshared watch just to apply
+// a namespace scope — the opposite of what the shared-informer pattern this
+// package is built on is for. Filtering after the shared cache has already
+// seen the object is the tradeoff that keeps the informer shared.
+type namespaceFilter struct {
+	selector        labels.Selector
+	namespaceLister corelisters.NamespaceLister
+
+	included sets.String
+	excluded sets.String
+}
+
+// Matches reports whether namespace is in scope.  The origin container event
+// handlers consult it before enqueueing; the sync functions consult it again
+// because the RBAC-side handler (naiveEventHandler) enqueues every namespace.
+func (f *namespaceFilter) Matches(namespace string) bool {
+	if f.excluded.Has(namespace) {
+		return false
+	}
+	if f.included.Len() > 0 && !f.included.Has(namespace) {
+		return false
+	}
+	if f.selector != nil && !f.selector.Empty() {
+		ns, err := f.namespaceLister.Get(namespace)
+		if err != nil {
+			utilruntime.HandleError(err)
+			return false
+		}
+		if !f.selector.Matches(labels.Set(ns.Labels)) {
+			return false
+		}
+	}
+	return true
+}
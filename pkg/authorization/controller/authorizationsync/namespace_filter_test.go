@@ -0,0 +1,79 @@
+package authorizationsync
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	kapi "k8s.io/kubernetes/pkg/api"
+	corelisters "k8s.io/kubernetes/pkg/client/listers/core/internalversion"
+)
+
+func namespaceListerWithLabels(namespaces map[string]map[string]string) corelisters.NamespaceLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for name, labels := range namespaces {
+		indexer.Add(&kapi.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}})
+	}
+	return corelisters.NewNamespaceLister(indexer)
+}
+
+func TestNamespaceFilterZeroValueMatchesEverything(t *testing.T) {
+	var f namespaceFilter
+	for _, ns := range []string{"default", "kube-system", "my-app"} {
+		if !f.Matches(ns) {
+			t.Errorf("expected zero-value filter to match %q", ns)
+		}
+	}
+}
+
+func TestNamespaceFilterWithNamespaces(t *testing.T) {
+	f := namespaceFilter{}
+	WithNamespaces([]string{"a", "b"})(&f)
+
+	if !f.Matches("a") {
+		t.Error("expected a to match")
+	}
+	if f.Matches("c") {
+		t.Error("expected c not to match")
+	}
+}
+
+func TestNamespaceFilterWithExcludedNamespaces(t *testing.T) {
+	f := namespaceFilter{}
+	WithNamespaces([]string{"a", "b"})(&f)
+	WithExcludedNamespaces([]string{"b"})(&f)
+
+	if !f.Matches("a") {
+		t.Error("expected a to match")
+	}
+	if f.Matches("b") {
+		t.Error("expected b to be excluded even though it is also included")
+	}
+}
+
+func TestNamespaceFilterWithNamespaceSelector(t *testing.T) {
+	lister := namespaceListerWithLabels(map[string]map[string]string{
+		"team-a": {"team": "a"},
+		"team-b": {"team": "b"},
+	})
+	f := namespaceFilter{}
+	WithNamespaceSelector(labels.SelectorFromSet(labels.Set{"team": "a"}), lister)(&f)
+
+	if !f.Matches("team-a") {
+		t.Error("expected team-a to match the selector")
+	}
+	if f.Matches("team-b") {
+		t.Error("expected team-b not to match the selector")
+	}
+}
+
+func TestNamespaceFilterWithNamespaceSelectorMissingNamespaceDoesNotMatch(t *testing.T) {
+	lister := namespaceListerWithLabels(map[string]map[string]string{})
+	f := namespaceFilter{}
+	WithNamespaceSelector(labels.SelectorFromSet(labels.Set{"team": "a"}), lister)(&f)
+
+	if f.Matches("does-not-exist") {
+		t.Error("expected a namespace the lister has never seen not to match")
+	}
+}
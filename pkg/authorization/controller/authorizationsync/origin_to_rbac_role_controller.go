@@ -3,15 +3,13 @@ package authorizationsync
 import (
 	"fmt"
 
-	"github.com/golang/glog"
-
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	kapi "k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/apis/rbac"
-	rbacclient "k8s.io/kubernetes/pkg/client/clientset_generated/internalclientset/typed/rbac/internalversion"
 	rbacinformers "k8s.io/kubernetes/pkg/client/informers/informers_generated/internalversion/rbac/internalversion"
 	rbaclister "k8s.io/kubernetes/pkg/client/listers/rbac/internalversion"
 	"k8s.io/kubernetes/pkg/controller"
@@ -22,29 +20,40 @@ import (
 )
 
 type OriginRoleToRBACRoleController struct {
-	rbacClient rbacclient.RolesGetter
+	applier RoleApplier
 
 	rbacLister    rbaclister.RoleLister
 	originIndexer cache.Indexer
 	originLister  originlister.RoleLister
 
+	namespaceFilter namespaceFilter
+
 	genericController
 }
 
-func NewOriginToRBACRoleController(rbacRoleInformer rbacinformers.RoleInformer, originPolicyInformer origininformers.PolicyInformer, rbacClient rbacclient.RolesGetter) *OriginRoleToRBACRoleController {
+func NewOriginToRBACRoleController(rbacRoleInformer rbacinformers.RoleInformer, originPolicyInformer origininformers.PolicyInformer, applier RoleApplier, eventRecorder record.EventRecorder, opts ...Option) *OriginRoleToRBACRoleController {
+	RegisterMetrics()
+	var filter namespaceFilter
+	for _, opt := range opts {
+		opt(&filter)
+	}
+
 	originIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
 	c := &OriginRoleToRBACRoleController{
-		rbacClient:    rbacClient,
+		applier:       applier,
 		rbacLister:    rbacRoleInformer.Lister(),
 		originIndexer: originIndexer,
 		originLister:  originlister.NewRoleLister(originIndexer),
 
+		namespaceFilter: filter,
+
 		genericController: genericController{
 			name: "OriginRoleToRBACRoleController",
 			cachesSynced: func() bool {
 				return rbacRoleInformer.Informer().HasSynced() && originPolicyInformer.Informer().HasSynced()
 			},
-			queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "origin-to-rbac-role"),
+			queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "origin-to-rbac-role"),
+			eventRecorder: eventRecorder,
 		},
 	}
 	c.genericController.syncFunc = c.syncRole
@@ -74,15 +83,34 @@ func (c *OriginRoleToRBACRoleController) syncRole(key string) error {
 	if apierrors.IsNotFound(rbacErr) && apierrors.IsNotFound(originErr) {
 		return nil
 	}
+	// the RBAC-side handler enqueues keys from every namespace, so scope them here
+	if !c.namespaceFilter.Matches(namespace) {
+		return nil
+	}
+	// an opt-out annotation on either side leaves both sides untouched
+	if originErr == nil && isSyncDisabled(originRole.Annotations) {
+		return nil
+	}
+	if rbacErr == nil && isSyncDisabled(rbacRole.Annotations) {
+		return nil
+	}
 	// if the origin role doesn't exist, just delete the rbac role
 	if apierrors.IsNotFound(originErr) {
-		// orphan on delete to minimize fanout.  We ought to clean the rest via controller too.
-		return c.rbacClient.Roles(namespace).Delete(name, nil)
+		result, err := c.applier.Delete(namespace, name)
+		if err == nil {
+			reason, verb := "OriginRoleDeleted", "deleted"
+			if result == RoleApplyResultWouldDelete {
+				reason, verb = "OriginRoleWouldBeDeleted", "would be deleted"
+			}
+			c.recordEvent(rbacRole, kapi.EventTypeNormal, reason, string(result), "%s because origin role %s/%s no longer exists", verb, namespace, name)
+		}
+		return err
 	}
 
 	// convert the origin role to an rbac role and compare the results
 	convertedRole := &rbac.Role{}
 	if err := authorizationapi.Convert_api_Role_To_rbac_Role(originRole, convertedRole, nil); err != nil {
+		conversionErrorsTotal.Inc()
 		return err
 	}
 	// do a deep copy here since conversion does not guarantee a new object.
@@ -90,11 +118,19 @@ func (c *OriginRoleToRBACRoleController) syncRole(key string) error {
 	if err := rbac.DeepCopy_rbac_Role(convertedRole, equivalentRole, cloner); err != nil {
 		return err
 	}
+	stampLastSync(equivalentRole, originRole.ResourceVersion)
 
 	// if we're missing the rbacRole, create it
 	if apierrors.IsNotFound(rbacErr) {
-		equivalentRole.ResourceVersion = ""
-		_, err := c.rbacClient.Roles(namespace).Create(equivalentRole)
+		result, err := c.applier.Apply(namespace, equivalentRole, nil)
+		if err == nil {
+			reason, verb := "RBACRoleCreated", "created"
+			if result == RoleApplyResultWouldCreate {
+				reason, verb = "RBACRoleWouldBeCreated", "would be created"
+			}
+			c.recordEvent(equivalentRole, kapi.EventTypeNormal, reason, string(result), "%s from origin role %s/%s", verb, namespace, name)
+			c.recordMirrorEvent(originRole, kapi.EventTypeNormal, reason, "RBAC role %s/%s %s from this origin role", namespace, name, verb)
+		}
 		return err
 	}
 
@@ -106,15 +142,25 @@ func (c *OriginRoleToRBACRoleController) syncRole(key string) error {
 
 	// if they're equal, we have no work to do
 	if kapi.Semantic.DeepEqual(equivalentRole, rbacRole) {
+		syncsTotal.WithLabelValues("none").Inc()
 		return nil
 	}
 
-	glog.V(1).Infof("writing RBAC role %v/%v", namespace, name)
-	_, err = c.rbacClient.Roles(namespace).Update(equivalentRole)
-	// if the update was invalid, we're probably changing an immutable field or something like that
-	// either way, the existing object is wrong.  Delete it and try again.
-	if apierrors.IsInvalid(err) {
-		c.rbacClient.Roles(namespace).Delete(name, nil)
+	result, err := c.applier.Apply(namespace, equivalentRole, rbacRole)
+	switch result {
+	case RoleApplyResultDeleted:
+		c.recordEvent(rbacRole, kapi.EventTypeWarning, "RBACRoleInvalidUpdate", string(result), "deleting RBAC role %s/%s after an invalid update: %v", namespace, name, err)
+		c.recordMirrorEvent(originRole, kapi.EventTypeWarning, "RBACRoleInvalidUpdate", "RBAC role %s/%s deleted after an invalid update from this origin role: %v", namespace, name, err)
+	case RoleApplyResultUpdated:
+		if err == nil {
+			c.recordEvent(equivalentRole, kapi.EventTypeNormal, "RBACRoleUpdated", string(result), "updated from origin role %s/%s", namespace, name)
+			c.recordMirrorEvent(originRole, kapi.EventTypeNormal, "RBACRoleUpdated", "RBAC role %s/%s updated from this origin role", namespace, name)
+		}
+	case RoleApplyResultWouldUpdate:
+		if err == nil {
+			c.recordEvent(equivalentRole, kapi.EventTypeNormal, "RBACRoleWouldBeUpdated", string(result), "would be updated from origin role %s/%s", namespace, name)
+			c.recordMirrorEvent(originRole, kapi.EventTypeNormal, "RBACRoleWouldBeUpdated", "RBAC role %s/%s would be updated from this origin role", namespace, name)
+		}
 	}
 	return err
 }
@@ -123,6 +169,9 @@ func (c *OriginRoleToRBACRoleController) policyEventHandler() cache.ResourceEven
 	return cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			originContainerObj := obj.(*authorizationapi.Policy)
+			if !c.namespaceFilter.Matches(originContainerObj.Namespace) {
+				return
+			}
 			for _, originObj := range originContainerObj.Roles {
 				c.originIndexer.Add(originObj)
 				key, err := controller.KeyFunc(originObj)
@@ -135,6 +184,9 @@ func (c *OriginRoleToRBACRoleController) policyEventHandler() cache.ResourceEven
 		},
 		UpdateFunc: func(old, cur interface{}) {
 			originContainerObj := cur.(*authorizationapi.Policy)
+			if !c.namespaceFilter.Matches(originContainerObj.Namespace) {
+				return
+			}
 			for _, originObj := range originContainerObj.Roles {
 				c.originIndexer.Add(originObj)
 				key, err := controller.KeyFunc(originObj)
@@ -157,6 +209,9 @@ func (c *OriginRoleToRBACRoleController) policyEventHandler() cache.ResourceEven
 					utilruntime.HandleError(fmt.Errorf("Tombstone contained object that is not a runtime.Object %#v", obj))
 				}
 			}
+			if !c.namespaceFilter.Matches(originContainerObj.Namespace) {
+				return
+			}
 
 			for _, originObj := range originContainerObj.Roles {
 				c.originIndexer.Add(originObj)
@@ -0,0 +1,275 @@
+package authorizationsync
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	kapi "k8s.io/kubernetes/pkg/api"
+	rbacinformers "k8s.io/kubernetes/pkg/client/informers/informers_generated/internalversion/rbac/internalversion"
+	rbaclister "k8s.io/kubernetes/pkg/client/listers/rbac/internalversion"
+	"k8s.io/kubernetes/pkg/controller"
+
+	authorizationapi "github.com/openshift/origin/pkg/authorization/api"
+	origininformers "github.com/openshift/origin/pkg/authorization/generated/informers/internalversion/authorization/internalversion"
+	authorizationclient "github.com/openshift/origin/pkg/authorization/generated/internalclientset/typed/authorization/internalversion"
+	originlister "github.com/openshift/origin/pkg/authorization/generated/listers/authorization/internalversion"
+)
+
+// clusterPolicyObjectName is the name of the singleton cluster-scoped
+// ClusterPolicy/ClusterPolicyBinding container object.
+const clusterPolicyObjectName = "default"
+
+// RBACClusterRoleToOriginClusterRoleController is the reverse of
+// OriginClusterRoleToRBACClusterRoleController: it watches RBAC cluster
+// roles and merges them into the origin ClusterPolicy container.
+type RBACClusterRoleToOriginClusterRoleController struct {
+	clusterPolicyClient authorizationclient.ClusterPoliciesGetter
+
+	rbacLister rbaclister.ClusterRoleLister
+
+	originIndexer cache.Indexer
+	originLister  originlister.ClusterRoleLister
+
+	conflictResolution ConflictResolution
+
+	genericController
+}
+
+func NewRBACToOriginClusterRoleController(rbacClusterRoleInformer rbacinformers.ClusterRoleInformer, originClusterPolicyInformer origininformers.ClusterPolicyInformer, clusterPolicyClient authorizationclient.ClusterPoliciesGetter, conflictResolution ConflictResolution, eventRecorder record.EventRecorder) *RBACClusterRoleToOriginClusterRoleController {
+	RegisterMetrics()
+	originIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	c := &RBACClusterRoleToOriginClusterRoleController{
+		clusterPolicyClient: clusterPolicyClient,
+
+		rbacLister: rbacClusterRoleInformer.Lister(),
+
+		originIndexer: originIndexer,
+		originLister:  originlister.NewClusterRoleLister(originIndexer),
+
+		conflictResolution: conflictResolution,
+
+		genericController: genericController{
+			name: "RBACClusterRoleToOriginClusterRoleController",
+			cachesSynced: func() bool {
+				return rbacClusterRoleInformer.Informer().HasSynced() && originClusterPolicyInformer.Informer().HasSynced()
+			},
+			queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "rbac-to-origin-clusterrole"),
+			eventRecorder: eventRecorder,
+		},
+	}
+	c.genericController.syncFunc = c.syncClusterRole
+
+	rbacClusterRoleInformer.Informer().AddEventHandler(naiveEventHandler(c.queue))
+	originClusterPolicyInformer.Informer().AddEventHandler(c.clusterPolicyEventHandler())
+
+	return c
+}
+
+func (c *RBACClusterRoleToOriginClusterRoleController) syncClusterRole(key string) error {
+	_, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	rbacClusterRole, rbacErr := c.rbacLister.Get(name)
+	if !apierrors.IsNotFound(rbacErr) && rbacErr != nil {
+		return rbacErr
+	}
+	originClusterRole, originErr := c.originLister.Get(name)
+	if !apierrors.IsNotFound(originErr) && originErr != nil {
+		return originErr
+	}
+
+	// if neither cluster role exists, return
+	if apierrors.IsNotFound(rbacErr) && apierrors.IsNotFound(originErr) {
+		return nil
+	}
+	// an opt-out annotation on either side leaves both sides untouched
+	if originErr == nil && isSyncDisabled(originClusterRole.Annotations) {
+		return nil
+	}
+	if rbacErr == nil && isSyncDisabled(rbacClusterRole.Annotations) {
+		return nil
+	}
+	// if the RBAC cluster role doesn't exist, remove it from the origin ClusterPolicy
+	if apierrors.IsNotFound(rbacErr) {
+		switch c.conflictResolution {
+		case ConflictResolutionOriginWins:
+			// leave origin alone; there is no RBAC side left to reconcile it against
+			return nil
+		case ConflictResolutionReject:
+			c.recordEvent(originClusterRole, kapi.EventTypeWarning, "ClusterRoleSyncConflict", "rejected", "cluster role %s exists in origin cluster policy %s but RBAC cluster role %s no longer exists; refusing to sync", name, clusterPolicyObjectName, name)
+			return fmt.Errorf("cluster role %s exists in origin cluster policy %s but RBAC cluster role %s no longer exists; refusing to sync", name, clusterPolicyObjectName, name)
+		}
+
+		err := c.deleteOriginClusterRole(name)
+		if err == nil {
+			c.recordEvent(originClusterRole, kapi.EventTypeNormal, "OriginClusterRoleDeleted", "deleted", "deleted from origin cluster policy %s because RBAC cluster role %s no longer exists", clusterPolicyObjectName, name)
+		}
+		return err
+	}
+
+	// convert the rbac cluster role to an origin cluster role and compare the results
+	convertedClusterRole := &authorizationapi.ClusterRole{}
+	if err := authorizationapi.Convert_rbac_ClusterRole_To_api_ClusterRole(rbacClusterRole, convertedClusterRole, nil); err != nil {
+		conversionErrorsTotal.Inc()
+		return err
+	}
+	// do a deep copy here since conversion does not guarantee a new object.
+	equivalentClusterRole := &authorizationapi.ClusterRole{}
+	if err := authorizationapi.DeepCopy_api_ClusterRole(convertedClusterRole, equivalentClusterRole, cloner); err != nil {
+		return err
+	}
+
+	// if we're missing the origin cluster role, create it
+	if apierrors.IsNotFound(originErr) {
+		switch c.conflictResolution {
+		case ConflictResolutionOriginWins:
+			// leave origin alone; there is no origin entry to reconcile RBAC against yet
+			return nil
+		case ConflictResolutionReject:
+			c.recordEvent(rbacClusterRole, kapi.EventTypeWarning, "ClusterRoleSyncConflict", "rejected", "RBAC cluster role %s has no entry in origin cluster policy %s; refusing to sync", name, clusterPolicyObjectName)
+			return fmt.Errorf("RBAC cluster role %s has no entry in origin cluster policy %s; refusing to sync", name, clusterPolicyObjectName)
+		}
+
+		err := c.applyOriginClusterRole(equivalentClusterRole)
+		if err == nil {
+			c.recordEvent(equivalentClusterRole, kapi.EventTypeNormal, "OriginClusterRoleCreated", "created", "created in origin cluster policy %s from RBAC cluster role %s", clusterPolicyObjectName, name)
+		}
+		return err
+	}
+
+	// stomp fields that are never going to match like uid and creation time
+	equivalentClusterRole.SelfLink = originClusterRole.SelfLink
+	equivalentClusterRole.UID = originClusterRole.UID
+	equivalentClusterRole.ResourceVersion = originClusterRole.ResourceVersion
+	equivalentClusterRole.CreationTimestamp = originClusterRole.CreationTimestamp
+
+	// if they're equal, we have no work to do
+	if kapi.Semantic.DeepEqual(equivalentClusterRole, originClusterRole) {
+		syncsTotal.WithLabelValues("none").Inc()
+		return nil
+	}
+
+	switch c.conflictResolution {
+	case ConflictResolutionOriginWins:
+		// leave origin alone; the origin->RBAC controller will stomp RBAC back on its next sync
+		return nil
+	case ConflictResolutionReject:
+		c.recordEvent(originClusterRole, kapi.EventTypeWarning, "ClusterRoleSyncConflict", "rejected", "cluster role %s has diverged between RBAC and origin cluster policy %s; refusing to sync", name, clusterPolicyObjectName)
+		return fmt.Errorf("cluster role %s has diverged between RBAC and origin cluster policy %s; refusing to sync", name, clusterPolicyObjectName)
+	}
+
+	err = c.applyOriginClusterRole(equivalentClusterRole)
+	if err == nil {
+		c.recordEvent(equivalentClusterRole, kapi.EventTypeNormal, "OriginClusterRoleUpdated", "updated", "updated in origin cluster policy %s from RBAC cluster role %s", clusterPolicyObjectName, name)
+	}
+	return err
+}
+
+func (c *RBACClusterRoleToOriginClusterRoleController) applyOriginClusterRole(clusterRole *authorizationapi.ClusterRole) error {
+	return retryOnConflict(func() error {
+		clusterPolicy, err := c.clusterPolicyClient.ClusterPolicies().Get(clusterPolicyObjectName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			clusterPolicy = &authorizationapi.ClusterPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: clusterPolicyObjectName},
+				Roles:      map[string]*authorizationapi.ClusterRole{},
+			}
+			clusterPolicy.Roles[clusterRole.Name] = clusterRole
+			glog.V(1).Infof("creating origin cluster policy %s from RBAC cluster role %v", clusterPolicyObjectName, clusterRole.Name)
+			_, err := c.clusterPolicyClient.ClusterPolicies().Create(clusterPolicy)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if clusterPolicy.Roles == nil {
+			clusterPolicy.Roles = map[string]*authorizationapi.ClusterRole{}
+		}
+		clusterPolicy.Roles[clusterRole.Name] = clusterRole
+
+		glog.V(1).Infof("writing origin cluster role %s from RBAC", clusterRole.Name)
+		_, err = c.clusterPolicyClient.ClusterPolicies().Update(clusterPolicy)
+		return err
+	})
+}
+
+func (c *RBACClusterRoleToOriginClusterRoleController) deleteOriginClusterRole(name string) error {
+	return retryOnConflict(func() error {
+		clusterPolicy, err := c.clusterPolicyClient.ClusterPolicies().Get(clusterPolicyObjectName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := clusterPolicy.Roles[name]; !ok {
+			return nil
+		}
+
+		delete(clusterPolicy.Roles, name)
+		glog.V(1).Infof("removing origin cluster role %s, RBAC cluster role deleted", name)
+		_, err = c.clusterPolicyClient.ClusterPolicies().Update(clusterPolicy)
+		return err
+	})
+}
+
+func (c *RBACClusterRoleToOriginClusterRoleController) clusterPolicyEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			originContainerObj := obj.(*authorizationapi.ClusterPolicy)
+			for _, originObj := range originContainerObj.Roles {
+				c.originIndexer.Add(originObj)
+				key, err := controller.KeyFunc(originObj)
+				if err != nil {
+					utilruntime.HandleError(err)
+					continue
+				}
+				c.queue.Add(key)
+			}
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			originContainerObj := cur.(*authorizationapi.ClusterPolicy)
+			for _, originObj := range originContainerObj.Roles {
+				c.originIndexer.Add(originObj)
+				key, err := controller.KeyFunc(originObj)
+				if err != nil {
+					utilruntime.HandleError(err)
+					continue
+				}
+				c.queue.Add(key)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			originContainerObj, ok := obj.(*authorizationapi.ClusterPolicy)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					utilruntime.HandleError(fmt.Errorf("Couldn't get object from tombstone %#v", obj))
+				}
+				originContainerObj, ok = tombstone.Obj.(*authorizationapi.ClusterPolicy)
+				if !ok {
+					utilruntime.HandleError(fmt.Errorf("Tombstone contained object that is not a runtime.Object %#v", obj))
+				}
+			}
+
+			for _, originObj := range originContainerObj.Roles {
+				c.originIndexer.Add(originObj)
+				key, err := controller.KeyFunc(originObj)
+				if err != nil {
+					utilruntime.HandleError(err)
+					continue
+				}
+				c.queue.Add(key)
+			}
+		},
+	}
+}
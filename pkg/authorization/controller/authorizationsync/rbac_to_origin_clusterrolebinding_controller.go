@@ -0,0 +1,272 @@
+package authorizationsync
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	kapi "k8s.io/kubernetes/pkg/api"
+	rbacinformers "k8s.io/kubernetes/pkg/client/informers/informers_generated/internalversion/rbac/internalversion"
+	rbaclister "k8s.io/kubernetes/pkg/client/listers/rbac/internalversion"
+	"k8s.io/kubernetes/pkg/controller"
+
+	authorizationapi "github.com/openshift/origin/pkg/authorization/api"
+	origininformers "github.com/openshift/origin/pkg/authorization/generated/informers/internalversion/authorization/internalversion"
+	authorizationclient "github.com/openshift/origin/pkg/authorization/generated/internalclientset/typed/authorization/internalversion"
+	originlister "github.com/openshift/origin/pkg/authorization/generated/listers/authorization/internalversion"
+)
+
+// RBACClusterRoleBindingToOriginClusterRoleBindingController is the reverse of
+// OriginClusterRoleBindingToRBACClusterRoleBindingController: it watches RBAC
+// cluster role bindings and merges them into the origin ClusterPolicyBinding
+// container.
+type RBACClusterRoleBindingToOriginClusterRoleBindingController struct {
+	clusterPolicyBindingClient authorizationclient.ClusterPolicyBindingsGetter
+
+	rbacLister rbaclister.ClusterRoleBindingLister
+
+	originIndexer cache.Indexer
+	originLister  originlister.ClusterRoleBindingLister
+
+	conflictResolution ConflictResolution
+
+	genericController
+}
+
+func NewRBACToOriginClusterRoleBindingController(rbacClusterRoleBindingInformer rbacinformers.ClusterRoleBindingInformer, originClusterPolicyBindingInformer origininformers.ClusterPolicyBindingInformer, clusterPolicyBindingClient authorizationclient.ClusterPolicyBindingsGetter, conflictResolution ConflictResolution, eventRecorder record.EventRecorder) *RBACClusterRoleBindingToOriginClusterRoleBindingController {
+	RegisterMetrics()
+	originIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	c := &RBACClusterRoleBindingToOriginClusterRoleBindingController{
+		clusterPolicyBindingClient: clusterPolicyBindingClient,
+
+		rbacLister: rbacClusterRoleBindingInformer.Lister(),
+
+		originIndexer: originIndexer,
+		originLister:  originlister.NewClusterRoleBindingLister(originIndexer),
+
+		conflictResolution: conflictResolution,
+
+		genericController: genericController{
+			name: "RBACClusterRoleBindingToOriginClusterRoleBindingController",
+			cachesSynced: func() bool {
+				return rbacClusterRoleBindingInformer.Informer().HasSynced() && originClusterPolicyBindingInformer.Informer().HasSynced()
+			},
+			queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "rbac-to-origin-clusterrolebinding"),
+			eventRecorder: eventRecorder,
+		},
+	}
+	c.genericController.syncFunc = c.syncClusterRoleBinding
+
+	rbacClusterRoleBindingInformer.Informer().AddEventHandler(naiveEventHandler(c.queue))
+	originClusterPolicyBindingInformer.Informer().AddEventHandler(c.clusterPolicyBindingEventHandler())
+
+	return c
+}
+
+func (c *RBACClusterRoleBindingToOriginClusterRoleBindingController) syncClusterRoleBinding(key string) error {
+	_, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	rbacClusterRoleBinding, rbacErr := c.rbacLister.Get(name)
+	if !apierrors.IsNotFound(rbacErr) && rbacErr != nil {
+		return rbacErr
+	}
+	originClusterRoleBinding, originErr := c.originLister.Get(name)
+	if !apierrors.IsNotFound(originErr) && originErr != nil {
+		return originErr
+	}
+
+	// if neither cluster role binding exists, return
+	if apierrors.IsNotFound(rbacErr) && apierrors.IsNotFound(originErr) {
+		return nil
+	}
+	// an opt-out annotation on either side leaves both sides untouched
+	if originErr == nil && isSyncDisabled(originClusterRoleBinding.Annotations) {
+		return nil
+	}
+	if rbacErr == nil && isSyncDisabled(rbacClusterRoleBinding.Annotations) {
+		return nil
+	}
+	// if the RBAC cluster role binding doesn't exist, remove it from the origin ClusterPolicyBinding
+	if apierrors.IsNotFound(rbacErr) {
+		switch c.conflictResolution {
+		case ConflictResolutionOriginWins:
+			// leave origin alone; there is no RBAC side left to reconcile it against
+			return nil
+		case ConflictResolutionReject:
+			c.recordEvent(originClusterRoleBinding, kapi.EventTypeWarning, "ClusterRoleBindingSyncConflict", "rejected", "cluster role binding %s exists in origin cluster policy binding %s but RBAC cluster role binding %s no longer exists; refusing to sync", name, clusterPolicyObjectName, name)
+			return fmt.Errorf("cluster role binding %s exists in origin cluster policy binding %s but RBAC cluster role binding %s no longer exists; refusing to sync", name, clusterPolicyObjectName, name)
+		}
+
+		err := c.deleteOriginClusterRoleBinding(name)
+		if err == nil {
+			c.recordEvent(originClusterRoleBinding, kapi.EventTypeNormal, "OriginClusterRoleBindingDeleted", "deleted", "deleted from origin cluster policy binding %s because RBAC cluster role binding %s no longer exists", clusterPolicyObjectName, name)
+		}
+		return err
+	}
+
+	// convert the rbac cluster role binding to an origin cluster role binding and compare the results
+	convertedClusterRoleBinding := &authorizationapi.ClusterRoleBinding{}
+	if err := authorizationapi.Convert_rbac_ClusterRoleBinding_To_api_ClusterRoleBinding(rbacClusterRoleBinding, convertedClusterRoleBinding, nil); err != nil {
+		conversionErrorsTotal.Inc()
+		return err
+	}
+	// do a deep copy here since conversion does not guarantee a new object.
+	equivalentClusterRoleBinding := &authorizationapi.ClusterRoleBinding{}
+	if err := authorizationapi.DeepCopy_api_ClusterRoleBinding(convertedClusterRoleBinding, equivalentClusterRoleBinding, cloner); err != nil {
+		return err
+	}
+
+	// if we're missing the origin cluster role binding, create it
+	if apierrors.IsNotFound(originErr) {
+		switch c.conflictResolution {
+		case ConflictResolutionOriginWins:
+			// leave origin alone; there is no origin entry to reconcile RBAC against yet
+			return nil
+		case ConflictResolutionReject:
+			c.recordEvent(rbacClusterRoleBinding, kapi.EventTypeWarning, "ClusterRoleBindingSyncConflict", "rejected", "RBAC cluster role binding %s has no entry in origin cluster policy binding %s; refusing to sync", name, clusterPolicyObjectName)
+			return fmt.Errorf("RBAC cluster role binding %s has no entry in origin cluster policy binding %s; refusing to sync", name, clusterPolicyObjectName)
+		}
+
+		err := c.applyOriginClusterRoleBinding(equivalentClusterRoleBinding)
+		if err == nil {
+			c.recordEvent(equivalentClusterRoleBinding, kapi.EventTypeNormal, "OriginClusterRoleBindingCreated", "created", "created in origin cluster policy binding %s from RBAC cluster role binding %s", clusterPolicyObjectName, name)
+		}
+		return err
+	}
+
+	// stomp fields that are never going to match like uid and creation time
+	equivalentClusterRoleBinding.SelfLink = originClusterRoleBinding.SelfLink
+	equivalentClusterRoleBinding.UID = originClusterRoleBinding.UID
+	equivalentClusterRoleBinding.ResourceVersion = originClusterRoleBinding.ResourceVersion
+	equivalentClusterRoleBinding.CreationTimestamp = originClusterRoleBinding.CreationTimestamp
+
+	// if they're equal, we have no work to do
+	if kapi.Semantic.DeepEqual(equivalentClusterRoleBinding, originClusterRoleBinding) {
+		syncsTotal.WithLabelValues("none").Inc()
+		return nil
+	}
+
+	switch c.conflictResolution {
+	case ConflictResolutionOriginWins:
+		// leave origin alone; the origin->RBAC controller will stomp RBAC back on its next sync
+		return nil
+	case ConflictResolutionReject:
+		c.recordEvent(originClusterRoleBinding, kapi.EventTypeWarning, "ClusterRoleBindingSyncConflict", "rejected", "cluster role binding %s has diverged between RBAC and origin cluster policy binding %s; refusing to sync", name, clusterPolicyObjectName)
+		return fmt.Errorf("cluster role binding %s has diverged between RBAC and origin cluster policy binding %s; refusing to sync", name, clusterPolicyObjectName)
+	}
+
+	err = c.applyOriginClusterRoleBinding(equivalentClusterRoleBinding)
+	if err == nil {
+		c.recordEvent(equivalentClusterRoleBinding, kapi.EventTypeNormal, "OriginClusterRoleBindingUpdated", "updated", "updated in origin cluster policy binding %s from RBAC cluster role binding %s", clusterPolicyObjectName, name)
+	}
+	return err
+}
+
+func (c *RBACClusterRoleBindingToOriginClusterRoleBindingController) applyOriginClusterRoleBinding(clusterRoleBinding *authorizationapi.ClusterRoleBinding) error {
+	return retryOnConflict(func() error {
+		clusterPolicyBinding, err := c.clusterPolicyBindingClient.ClusterPolicyBindings().Get(clusterPolicyObjectName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			clusterPolicyBinding = &authorizationapi.ClusterPolicyBinding{
+				ObjectMeta:   metav1.ObjectMeta{Name: clusterPolicyObjectName},
+				RoleBindings: map[string]*authorizationapi.ClusterRoleBinding{},
+			}
+			clusterPolicyBinding.RoleBindings[clusterRoleBinding.Name] = clusterRoleBinding
+			glog.V(1).Infof("creating origin cluster policy binding %s from RBAC cluster role binding %v", clusterPolicyObjectName, clusterRoleBinding.Name)
+			_, err := c.clusterPolicyBindingClient.ClusterPolicyBindings().Create(clusterPolicyBinding)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if clusterPolicyBinding.RoleBindings == nil {
+			clusterPolicyBinding.RoleBindings = map[string]*authorizationapi.ClusterRoleBinding{}
+		}
+		clusterPolicyBinding.RoleBindings[clusterRoleBinding.Name] = clusterRoleBinding
+
+		glog.V(1).Infof("writing origin cluster role binding %s from RBAC", clusterRoleBinding.Name)
+		_, err = c.clusterPolicyBindingClient.ClusterPolicyBindings().Update(clusterPolicyBinding)
+		return err
+	})
+}
+
+func (c *RBACClusterRoleBindingToOriginClusterRoleBindingController) deleteOriginClusterRoleBinding(name string) error {
+	return retryOnConflict(func() error {
+		clusterPolicyBinding, err := c.clusterPolicyBindingClient.ClusterPolicyBindings().Get(clusterPolicyObjectName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := clusterPolicyBinding.RoleBindings[name]; !ok {
+			return nil
+		}
+
+		delete(clusterPolicyBinding.RoleBindings, name)
+		glog.V(1).Infof("removing origin cluster role binding %s, RBAC cluster role binding deleted", name)
+		_, err = c.clusterPolicyBindingClient.ClusterPolicyBindings().Update(clusterPolicyBinding)
+		return err
+	})
+}
+
+func (c *RBACClusterRoleBindingToOriginClusterRoleBindingController) clusterPolicyBindingEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			originContainerObj := obj.(*authorizationapi.ClusterPolicyBinding)
+			for _, originObj := range originContainerObj.RoleBindings {
+				c.originIndexer.Add(originObj)
+				key, err := controller.KeyFunc(originObj)
+				if err != nil {
+					utilruntime.HandleError(err)
+					continue
+				}
+				c.queue.Add(key)
+			}
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			originContainerObj := cur.(*authorizationapi.ClusterPolicyBinding)
+			for _, originObj := range originContainerObj.RoleBindings {
+				c.originIndexer.Add(originObj)
+				key, err := controller.KeyFunc(originObj)
+				if err != nil {
+					utilruntime.HandleError(err)
+					continue
+				}
+				c.queue.Add(key)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			originContainerObj, ok := obj.(*authorizationapi.ClusterPolicyBinding)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					utilruntime.HandleError(fmt.Errorf("Couldn't get object from tombstone %#v", obj))
+				}
+				originContainerObj, ok = tombstone.Obj.(*authorizationapi.ClusterPolicyBinding)
+				if !ok {
+					utilruntime.HandleError(fmt.Errorf("Tombstone contained object that is not a runtime.Object %#v", obj))
+				}
+			}
+
+			for _, originObj := range originContainerObj.RoleBindings {
+				c.originIndexer.Add(originObj)
+				key, err := controller.KeyFunc(originObj)
+				if err != nil {
+					utilruntime.HandleError(err)
+					continue
+				}
+				c.queue.Add(key)
+			}
+		},
+	}
+}
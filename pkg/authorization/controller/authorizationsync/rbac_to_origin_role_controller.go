@@ -0,0 +1,302 @@
+package authorizationsync
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	kapi "k8s.io/kubernetes/pkg/api"
+	rbacinformers "k8s.io/kubernetes/pkg/client/informers/informers_generated/internalversion/rbac/internalversion"
+	rbaclister "k8s.io/kubernetes/pkg/client/listers/rbac/internalversion"
+	"k8s.io/kubernetes/pkg/controller"
+
+	authorizationapi "github.com/openshift/origin/pkg/authorization/api"
+	origininformers "github.com/openshift/origin/pkg/authorization/generated/informers/internalversion/authorization/internalversion"
+	authorizationclient "github.com/openshift/origin/pkg/authorization/generated/internalclientset/typed/authorization/internalversion"
+	originlister "github.com/openshift/origin/pkg/authorization/generated/listers/authorization/internalversion"
+)
+
+// policyObjectName is the name of the singleton Policy/PolicyBinding
+// container object origin maintains per namespace.
+const policyObjectName = "default"
+
+// RBACRoleToOriginRoleController is the reverse of OriginRoleToRBACRoleController:
+// it watches RBAC roles and merges them into the origin Policy container so
+// that an admin who edits RBAC directly keeps origin clients working.
+type RBACRoleToOriginRoleController struct {
+	policyClient authorizationclient.PoliciesGetter
+
+	rbacLister rbaclister.RoleLister
+
+	originIndexer cache.Indexer
+	originLister  originlister.RoleLister
+
+	conflictResolution ConflictResolution
+
+	namespaceFilter namespaceFilter
+
+	genericController
+}
+
+func NewRBACToOriginRoleController(rbacRoleInformer rbacinformers.RoleInformer, originPolicyInformer origininformers.PolicyInformer, policyClient authorizationclient.PoliciesGetter, conflictResolution ConflictResolution, eventRecorder record.EventRecorder, opts ...Option) *RBACRoleToOriginRoleController {
+	RegisterMetrics()
+	var filter namespaceFilter
+	for _, opt := range opts {
+		opt(&filter)
+	}
+
+	originIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	c := &RBACRoleToOriginRoleController{
+		policyClient: policyClient,
+
+		rbacLister: rbacRoleInformer.Lister(),
+
+		originIndexer: originIndexer,
+		originLister:  originlister.NewRoleLister(originIndexer),
+
+		conflictResolution: conflictResolution,
+
+		namespaceFilter: filter,
+
+		genericController: genericController{
+			name: "RBACRoleToOriginRoleController",
+			cachesSynced: func() bool {
+				return rbacRoleInformer.Informer().HasSynced() && originPolicyInformer.Informer().HasSynced()
+			},
+			queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "rbac-to-origin-role"),
+			eventRecorder: eventRecorder,
+		},
+	}
+	c.genericController.syncFunc = c.syncRole
+
+	rbacRoleInformer.Informer().AddEventHandler(naiveEventHandler(c.queue))
+	originPolicyInformer.Informer().AddEventHandler(c.policyEventHandler())
+
+	return c
+}
+
+func (c *RBACRoleToOriginRoleController) syncRole(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	rbacRole, rbacErr := c.rbacLister.Roles(namespace).Get(name)
+	if !apierrors.IsNotFound(rbacErr) && rbacErr != nil {
+		return rbacErr
+	}
+	originRole, originErr := c.originLister.Roles(namespace).Get(name)
+	if !apierrors.IsNotFound(originErr) && originErr != nil {
+		return originErr
+	}
+
+	// if neither role exists, return
+	if apierrors.IsNotFound(rbacErr) && apierrors.IsNotFound(originErr) {
+		return nil
+	}
+	// the RBAC-side handler enqueues keys from every namespace, so scope them here
+	if !c.namespaceFilter.Matches(namespace) {
+		return nil
+	}
+	// an opt-out annotation on either side leaves both sides untouched
+	if originErr == nil && isSyncDisabled(originRole.Annotations) {
+		return nil
+	}
+	if rbacErr == nil && isSyncDisabled(rbacRole.Annotations) {
+		return nil
+	}
+	// if the RBAC role doesn't exist, remove the role from the origin Policy
+	if apierrors.IsNotFound(rbacErr) {
+		switch c.conflictResolution {
+		case ConflictResolutionOriginWins:
+			// leave origin alone; there is no RBAC side left to reconcile it against
+			return nil
+		case ConflictResolutionReject:
+			c.recordEvent(originRole, kapi.EventTypeWarning, "RoleSyncConflict", "rejected", "role %s/%s exists in origin policy %s but RBAC role %s no longer exists; refusing to sync", namespace, name, policyObjectName, name)
+			return fmt.Errorf("role %s/%s exists in origin policy %s but RBAC role %s no longer exists; refusing to sync", namespace, name, policyObjectName, name)
+		}
+
+		err := c.deleteOriginRole(namespace, name)
+		if err == nil {
+			c.recordEvent(originRole, kapi.EventTypeNormal, "OriginRoleDeleted", "deleted", "deleted from origin policy %s/%s because RBAC role %s no longer exists", namespace, policyObjectName, name)
+		}
+		return err
+	}
+
+	// convert the rbac role to an origin role and compare the results
+	convertedRole := &authorizationapi.Role{}
+	if err := authorizationapi.Convert_rbac_Role_To_api_Role(rbacRole, convertedRole, nil); err != nil {
+		conversionErrorsTotal.Inc()
+		return err
+	}
+	// do a deep copy here since conversion does not guarantee a new object.
+	equivalentRole := &authorizationapi.Role{}
+	if err := authorizationapi.DeepCopy_api_Role(convertedRole, equivalentRole, cloner); err != nil {
+		return err
+	}
+
+	// if we're missing the origin role, create it
+	if apierrors.IsNotFound(originErr) {
+		switch c.conflictResolution {
+		case ConflictResolutionOriginWins:
+			// leave origin alone; there is no origin entry to reconcile RBAC against yet
+			return nil
+		case ConflictResolutionReject:
+			c.recordEvent(rbacRole, kapi.EventTypeWarning, "RoleSyncConflict", "rejected", "RBAC role %s/%s has no entry in origin policy %s; refusing to sync", namespace, name, policyObjectName)
+			return fmt.Errorf("RBAC role %s/%s has no entry in origin policy %s; refusing to sync", namespace, name, policyObjectName)
+		}
+
+		err := c.applyOriginRole(namespace, equivalentRole)
+		if err == nil {
+			c.recordEvent(equivalentRole, kapi.EventTypeNormal, "OriginRoleCreated", "created", "created in origin policy %s/%s from RBAC role %s", namespace, policyObjectName, name)
+		}
+		return err
+	}
+
+	// stomp fields that are never going to match like uid and creation time
+	equivalentRole.SelfLink = originRole.SelfLink
+	equivalentRole.UID = originRole.UID
+	equivalentRole.ResourceVersion = originRole.ResourceVersion
+	equivalentRole.CreationTimestamp = originRole.CreationTimestamp
+
+	// if they're equal, we have no work to do
+	if kapi.Semantic.DeepEqual(equivalentRole, originRole) {
+		syncsTotal.WithLabelValues("none").Inc()
+		return nil
+	}
+
+	switch c.conflictResolution {
+	case ConflictResolutionOriginWins:
+		// leave origin alone; the origin->RBAC controller will stomp RBAC back on its next sync
+		return nil
+	case ConflictResolutionReject:
+		c.recordEvent(originRole, kapi.EventTypeWarning, "RoleSyncConflict", "rejected", "role %s/%s has diverged between RBAC and origin policy %s; refusing to sync", namespace, name, policyObjectName)
+		return fmt.Errorf("role %s/%s has diverged between RBAC and origin policy %s; refusing to sync", namespace, name, policyObjectName)
+	}
+
+	err = c.applyOriginRole(namespace, equivalentRole)
+	if err == nil {
+		c.recordEvent(equivalentRole, kapi.EventTypeNormal, "OriginRoleUpdated", "updated", "updated in origin policy %s/%s from RBAC role %s", namespace, policyObjectName, name)
+	}
+	return err
+}
+
+// applyOriginRole merges role into the namespace's Policy object, creating
+// the Policy if it does not yet exist, retrying on update conflicts since
+// every role in the namespace shares the same container object.
+func (c *RBACRoleToOriginRoleController) applyOriginRole(namespace string, role *authorizationapi.Role) error {
+	return retryOnConflict(func() error {
+		policy, err := c.policyClient.Policies(namespace).Get(policyObjectName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			policy = &authorizationapi.Policy{
+				ObjectMeta: metav1.ObjectMeta{Name: policyObjectName, Namespace: namespace},
+				Roles:      map[string]*authorizationapi.Role{},
+			}
+			policy.Roles[role.Name] = role
+			glog.V(1).Infof("creating origin policy %s/%s from RBAC role %v", namespace, policyObjectName, role.Name)
+			_, err := c.policyClient.Policies(namespace).Create(policy)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if policy.Roles == nil {
+			policy.Roles = map[string]*authorizationapi.Role{}
+		}
+		policy.Roles[role.Name] = role
+
+		glog.V(1).Infof("writing origin role %s/%s from RBAC", namespace, role.Name)
+		_, err = c.policyClient.Policies(namespace).Update(policy)
+		return err
+	})
+}
+
+// deleteOriginRole removes name from the namespace's Policy object, retrying
+// on update conflicts.
+func (c *RBACRoleToOriginRoleController) deleteOriginRole(namespace, name string) error {
+	return retryOnConflict(func() error {
+		policy, err := c.policyClient.Policies(namespace).Get(policyObjectName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := policy.Roles[name]; !ok {
+			return nil
+		}
+
+		delete(policy.Roles, name)
+		glog.V(1).Infof("removing origin role %s/%s, RBAC role deleted", namespace, name)
+		_, err = c.policyClient.Policies(namespace).Update(policy)
+		return err
+	})
+}
+
+func (c *RBACRoleToOriginRoleController) policyEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			originContainerObj := obj.(*authorizationapi.Policy)
+			if !c.namespaceFilter.Matches(originContainerObj.Namespace) {
+				return
+			}
+			for _, originObj := range originContainerObj.Roles {
+				c.originIndexer.Add(originObj)
+				key, err := controller.KeyFunc(originObj)
+				if err != nil {
+					utilruntime.HandleError(err)
+					continue
+				}
+				c.queue.Add(key)
+			}
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			originContainerObj := cur.(*authorizationapi.Policy)
+			if !c.namespaceFilter.Matches(originContainerObj.Namespace) {
+				return
+			}
+			for _, originObj := range originContainerObj.Roles {
+				c.originIndexer.Add(originObj)
+				key, err := controller.KeyFunc(originObj)
+				if err != nil {
+					utilruntime.HandleError(err)
+					continue
+				}
+				c.queue.Add(key)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			originContainerObj, ok := obj.(*authorizationapi.Policy)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					utilruntime.HandleError(fmt.Errorf("Couldn't get object from tombstone %#v", obj))
+				}
+				originContainerObj, ok = tombstone.Obj.(*authorizationapi.Policy)
+				if !ok {
+					utilruntime.HandleError(fmt.Errorf("Tombstone contained object that is not a runtime.Object %#v", obj))
+				}
+			}
+			if !c.namespaceFilter.Matches(originContainerObj.Namespace) {
+				return
+			}
+
+			for _, originObj := range originContainerObj.Roles {
+				c.originIndexer.Add(originObj)
+				key, err := controller.KeyFunc(originObj)
+				if err != nil {
+					utilruntime.HandleError(err)
+					continue
+				}
+				c.queue.Add(key)
+			}
+		},
+	}
+}
@@ -0,0 +1,206 @@
+package authorizationsync
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/kubernetes/pkg/apis/rbac"
+	rbaclister "k8s.io/kubernetes/pkg/client/listers/rbac/internalversion"
+
+	authorizationapi "github.com/openshift/origin/pkg/authorization/api"
+	fakeauthorizationclient "github.com/openshift/origin/pkg/authorization/generated/internalclientset/fake"
+	originlister "github.com/openshift/origin/pkg/authorization/generated/listers/authorization/internalversion"
+)
+
+// newTestRBACToOriginRoleController builds a RBACRoleToOriginRoleController
+// with its listers pre-populated and its client/resolution swapped in,
+// bypassing the informer-wiring NewRBACToOriginRoleController does so each
+// test can drive syncRole directly against a known starting state.
+func newTestRBACToOriginRoleController(t *testing.T, conflictResolution ConflictResolution, rbacRole *rbac.Role, originRole *authorizationapi.Role, policyClient *fakeauthorizationclient.Clientset) *RBACRoleToOriginRoleController {
+	t.Helper()
+
+	rbacIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	if rbacRole != nil {
+		if err := rbacIndexer.Add(rbacRole); err != nil {
+			t.Fatalf("adding rbac role: %v", err)
+		}
+	}
+
+	originIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	if originRole != nil {
+		if err := originIndexer.Add(originRole); err != nil {
+			t.Fatalf("adding origin role: %v", err)
+		}
+	}
+
+	return &RBACRoleToOriginRoleController{
+		policyClient: policyClient.Authorization(),
+
+		rbacLister: rbaclister.NewRoleLister(rbacIndexer),
+
+		originIndexer: originIndexer,
+		originLister:  originlister.NewRoleLister(originIndexer),
+
+		conflictResolution: conflictResolution,
+
+		genericController: genericController{
+			eventRecorder: &record.FakeRecorder{Events: make(chan string, 10)},
+		},
+	}
+}
+
+func divergentRoles(namespace, name string) (*rbac.Role, *authorizationapi.Role) {
+	rbacRole := &rbac.Role{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Rules:      []rbac.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}},
+	}
+	originRole := &authorizationapi.Role{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Rules:      []authorizationapi.PolicyRule{{Verbs: []string{"list"}, APIGroups: []string{""}, Resources: []string{"pods"}}},
+	}
+	return rbacRole, originRole
+}
+
+func TestSyncRoleConflictResolutionOriginWinsLeavesOriginUntouched(t *testing.T) {
+	rbacRole, originRole := divergentRoles("ns", "edit")
+	policy := &authorizationapi.Policy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: policyObjectName},
+		Roles:      map[string]*authorizationapi.Role{"edit": originRole},
+	}
+	client := fakeauthorizationclient.NewSimpleClientset(policy)
+	c := newTestRBACToOriginRoleController(t, ConflictResolutionOriginWins, rbacRole, originRole, client)
+
+	if err := c.syncRole("ns/edit"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := client.Authorization().Policies("ns").Get(policyObjectName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error fetching policy: %v", err)
+	}
+	if len(got.Roles["edit"].Rules) != 1 || got.Roles["edit"].Rules[0].Verbs[0] != "list" {
+		t.Errorf("expected origin role to be left untouched, got %+v", got.Roles["edit"])
+	}
+}
+
+func TestSyncRoleConflictResolutionRejectReturnsError(t *testing.T) {
+	rbacRole, originRole := divergentRoles("ns", "edit")
+	policy := &authorizationapi.Policy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: policyObjectName},
+		Roles:      map[string]*authorizationapi.Role{"edit": originRole},
+	}
+	client := fakeauthorizationclient.NewSimpleClientset(policy)
+	c := newTestRBACToOriginRoleController(t, ConflictResolutionReject, rbacRole, originRole, client)
+
+	err := c.syncRole("ns/edit")
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+
+	got, err := client.Authorization().Policies("ns").Get(policyObjectName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error fetching policy: %v", err)
+	}
+	if len(got.Roles["edit"].Rules) != 1 || got.Roles["edit"].Rules[0].Verbs[0] != "list" {
+		t.Errorf("expected origin role to be left untouched, got %+v", got.Roles["edit"])
+	}
+}
+
+func TestSyncRoleConflictResolutionGatesCreatingAMissingOriginRole(t *testing.T) {
+	rbacRole := &rbac.Role{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "edit"}}
+
+	for _, tc := range []struct {
+		name          string
+		resolution    ConflictResolution
+		expectCreated bool
+		expectSyncErr bool
+	}{
+		{name: "RBACWins creates", resolution: ConflictResolutionRBACWins, expectCreated: true},
+		{name: "OriginWins leaves origin missing", resolution: ConflictResolutionOriginWins, expectCreated: false},
+		{name: "Reject leaves origin missing and errors", resolution: ConflictResolutionReject, expectCreated: false, expectSyncErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakeauthorizationclient.NewSimpleClientset()
+			c := newTestRBACToOriginRoleController(t, tc.resolution, rbacRole, nil, client)
+
+			err := c.syncRole("ns/edit")
+			if tc.expectSyncErr && err == nil {
+				t.Fatal("expected an error, got nil")
+			}
+			if !tc.expectSyncErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, getErr := client.Authorization().Policies("ns").Get(policyObjectName, metav1.GetOptions{})
+			created := getErr == nil
+			if created != tc.expectCreated {
+				t.Errorf("expected created=%v, got created=%v (get error: %v)", tc.expectCreated, created, getErr)
+			}
+		})
+	}
+}
+
+func TestSyncRoleConflictResolutionGatesDeletingAnOrphanedOriginRole(t *testing.T) {
+	originRole := &authorizationapi.Role{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "edit"}}
+	policy := &authorizationapi.Policy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: policyObjectName},
+		Roles:      map[string]*authorizationapi.Role{"edit": originRole},
+	}
+
+	for _, tc := range []struct {
+		name          string
+		resolution    ConflictResolution
+		expectDeleted bool
+		expectSyncErr bool
+	}{
+		{name: "RBACWins deletes", resolution: ConflictResolutionRBACWins, expectDeleted: true},
+		{name: "OriginWins leaves origin in place", resolution: ConflictResolutionOriginWins, expectDeleted: false},
+		{name: "Reject leaves origin in place and errors", resolution: ConflictResolutionReject, expectDeleted: false, expectSyncErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakeauthorizationclient.NewSimpleClientset(policy)
+			c := newTestRBACToOriginRoleController(t, tc.resolution, nil, originRole, client)
+
+			err := c.syncRole("ns/edit")
+			if tc.expectSyncErr && err == nil {
+				t.Fatal("expected an error, got nil")
+			}
+			if !tc.expectSyncErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, getErr := client.Authorization().Policies("ns").Get(policyObjectName, metav1.GetOptions{})
+			if getErr != nil {
+				t.Fatalf("unexpected error fetching policy: %v", getErr)
+			}
+			_, stillPresent := got.Roles["edit"]
+			if stillPresent == tc.expectDeleted {
+				t.Errorf("expected deleted=%v, got deleted=%v", tc.expectDeleted, !stillPresent)
+			}
+		})
+	}
+}
+
+func TestSyncRoleConflictResolutionRBACWinsStompsOrigin(t *testing.T) {
+	rbacRole, originRole := divergentRoles("ns", "edit")
+	policy := &authorizationapi.Policy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: policyObjectName},
+		Roles:      map[string]*authorizationapi.Role{"edit": originRole},
+	}
+	client := fakeauthorizationclient.NewSimpleClientset(policy)
+	c := newTestRBACToOriginRoleController(t, ConflictResolutionRBACWins, rbacRole, originRole, client)
+
+	if err := c.syncRole("ns/edit"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := client.Authorization().Policies("ns").Get(policyObjectName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error fetching policy: %v", err)
+	}
+	if len(got.Roles["edit"].Rules) != 1 || got.Roles["edit"].Rules[0].Verbs[0] != "get" {
+		t.Errorf("expected origin role to be stomped to match RBAC, got %+v", got.Roles["edit"])
+	}
+}
@@ -0,0 +1,293 @@
+package authorizationsync
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	kapi "k8s.io/kubernetes/pkg/api"
+	rbacinformers "k8s.io/kubernetes/pkg/client/informers/informers_generated/internalversion/rbac/internalversion"
+	rbaclister "k8s.io/kubernetes/pkg/client/listers/rbac/internalversion"
+	"k8s.io/kubernetes/pkg/controller"
+
+	authorizationapi "github.com/openshift/origin/pkg/authorization/api"
+	origininformers "github.com/openshift/origin/pkg/authorization/generated/informers/internalversion/authorization/internalversion"
+	authorizationclient "github.com/openshift/origin/pkg/authorization/generated/internalclientset/typed/authorization/internalversion"
+	originlister "github.com/openshift/origin/pkg/authorization/generated/listers/authorization/internalversion"
+)
+
+// RBACRoleBindingToOriginRoleBindingController syncs in the RBAC->origin
+// direction: it watches RBAC role bindings and merges them into the origin
+// PolicyBinding container.
+type RBACRoleBindingToOriginRoleBindingController struct {
+	policyBindingClient authorizationclient.PolicyBindingsGetter
+
+	rbacLister rbaclister.RoleBindingLister
+
+	originIndexer cache.Indexer
+	originLister  originlister.RoleBindingLister
+
+	conflictResolution ConflictResolution
+
+	namespaceFilter namespaceFilter
+
+	genericController
+}
+
+func NewRBACToOriginRoleBindingController(rbacRoleBindingInformer rbacinformers.RoleBindingInformer, originPolicyBindingInformer origininformers.PolicyBindingInformer, policyBindingClient authorizationclient.PolicyBindingsGetter, conflictResolution ConflictResolution, eventRecorder record.EventRecorder, opts ...Option) *RBACRoleBindingToOriginRoleBindingController {
+	RegisterMetrics()
+	var filter namespaceFilter
+	for _, opt := range opts {
+		opt(&filter)
+	}
+
+	originIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	c := &RBACRoleBindingToOriginRoleBindingController{
+		policyBindingClient: policyBindingClient,
+
+		rbacLister: rbacRoleBindingInformer.Lister(),
+
+		originIndexer: originIndexer,
+		originLister:  originlister.NewRoleBindingLister(originIndexer),
+
+		conflictResolution: conflictResolution,
+
+		namespaceFilter: filter,
+
+		genericController: genericController{
+			name: "RBACRoleBindingToOriginRoleBindingController",
+			cachesSynced: func() bool {
+				return rbacRoleBindingInformer.Informer().HasSynced() && originPolicyBindingInformer.Informer().HasSynced()
+			},
+			queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "rbac-to-origin-rolebinding"),
+			eventRecorder: eventRecorder,
+		},
+	}
+	c.genericController.syncFunc = c.syncRoleBinding
+
+	rbacRoleBindingInformer.Informer().AddEventHandler(naiveEventHandler(c.queue))
+	originPolicyBindingInformer.Informer().AddEventHandler(c.policyBindingEventHandler())
+
+	return c
+}
+
+func (c *RBACRoleBindingToOriginRoleBindingController) syncRoleBinding(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	rbacRoleBinding, rbacErr := c.rbacLister.RoleBindings(namespace).Get(name)
+	if !apierrors.IsNotFound(rbacErr) && rbacErr != nil {
+		return rbacErr
+	}
+	originRoleBinding, originErr := c.originLister.RoleBindings(namespace).Get(name)
+	if !apierrors.IsNotFound(originErr) && originErr != nil {
+		return originErr
+	}
+
+	// if neither role binding exists, return
+	if apierrors.IsNotFound(rbacErr) && apierrors.IsNotFound(originErr) {
+		return nil
+	}
+	// the RBAC-side handler enqueues keys from every namespace, so scope them here
+	if !c.namespaceFilter.Matches(namespace) {
+		return nil
+	}
+	// an opt-out annotation on either side leaves both sides untouched
+	if originErr == nil && isSyncDisabled(originRoleBinding.Annotations) {
+		return nil
+	}
+	if rbacErr == nil && isSyncDisabled(rbacRoleBinding.Annotations) {
+		return nil
+	}
+	// if the RBAC role binding doesn't exist, remove it from the origin PolicyBinding
+	if apierrors.IsNotFound(rbacErr) {
+		switch c.conflictResolution {
+		case ConflictResolutionOriginWins:
+			// leave origin alone; there is no RBAC side left to reconcile it against
+			return nil
+		case ConflictResolutionReject:
+			c.recordEvent(originRoleBinding, kapi.EventTypeWarning, "RoleBindingSyncConflict", "rejected", "role binding %s/%s exists in origin policy binding %s but RBAC role binding %s no longer exists; refusing to sync", namespace, name, policyObjectName, name)
+			return fmt.Errorf("role binding %s/%s exists in origin policy binding %s but RBAC role binding %s no longer exists; refusing to sync", namespace, name, policyObjectName, name)
+		}
+
+		err := c.deleteOriginRoleBinding(namespace, name)
+		if err == nil {
+			c.recordEvent(originRoleBinding, kapi.EventTypeNormal, "OriginRoleBindingDeleted", "deleted", "deleted from origin policy binding %s/%s because RBAC role binding %s no longer exists", namespace, policyObjectName, name)
+		}
+		return err
+	}
+
+	// convert the rbac role binding to an origin role binding and compare the results
+	convertedRoleBinding := &authorizationapi.RoleBinding{}
+	if err := authorizationapi.Convert_rbac_RoleBinding_To_api_RoleBinding(rbacRoleBinding, convertedRoleBinding, nil); err != nil {
+		conversionErrorsTotal.Inc()
+		return err
+	}
+	// do a deep copy here since conversion does not guarantee a new object.
+	equivalentRoleBinding := &authorizationapi.RoleBinding{}
+	if err := authorizationapi.DeepCopy_api_RoleBinding(convertedRoleBinding, equivalentRoleBinding, cloner); err != nil {
+		return err
+	}
+
+	// if we're missing the origin role binding, create it
+	if apierrors.IsNotFound(originErr) {
+		switch c.conflictResolution {
+		case ConflictResolutionOriginWins:
+			// leave origin alone; there is no origin entry to reconcile RBAC against yet
+			return nil
+		case ConflictResolutionReject:
+			c.recordEvent(rbacRoleBinding, kapi.EventTypeWarning, "RoleBindingSyncConflict", "rejected", "RBAC role binding %s/%s has no entry in origin policy binding %s; refusing to sync", namespace, name, policyObjectName)
+			return fmt.Errorf("RBAC role binding %s/%s has no entry in origin policy binding %s; refusing to sync", namespace, name, policyObjectName)
+		}
+
+		err := c.applyOriginRoleBinding(namespace, equivalentRoleBinding)
+		if err == nil {
+			c.recordEvent(equivalentRoleBinding, kapi.EventTypeNormal, "OriginRoleBindingCreated", "created", "created in origin policy binding %s/%s from RBAC role binding %s", namespace, policyObjectName, name)
+		}
+		return err
+	}
+
+	// stomp fields that are never going to match like uid and creation time
+	equivalentRoleBinding.SelfLink = originRoleBinding.SelfLink
+	equivalentRoleBinding.UID = originRoleBinding.UID
+	equivalentRoleBinding.ResourceVersion = originRoleBinding.ResourceVersion
+	equivalentRoleBinding.CreationTimestamp = originRoleBinding.CreationTimestamp
+
+	// if they're equal, we have no work to do
+	if kapi.Semantic.DeepEqual(equivalentRoleBinding, originRoleBinding) {
+		syncsTotal.WithLabelValues("none").Inc()
+		return nil
+	}
+
+	switch c.conflictResolution {
+	case ConflictResolutionOriginWins:
+		// leave origin alone; the origin->RBAC controller will stomp RBAC back on its next sync
+		return nil
+	case ConflictResolutionReject:
+		c.recordEvent(originRoleBinding, kapi.EventTypeWarning, "RoleBindingSyncConflict", "rejected", "role binding %s/%s has diverged between RBAC and origin policy binding %s; refusing to sync", namespace, name, policyObjectName)
+		return fmt.Errorf("role binding %s/%s has diverged between RBAC and origin policy binding %s; refusing to sync", namespace, name, policyObjectName)
+	}
+
+	err = c.applyOriginRoleBinding(namespace, equivalentRoleBinding)
+	if err == nil {
+		c.recordEvent(equivalentRoleBinding, kapi.EventTypeNormal, "OriginRoleBindingUpdated", "updated", "updated in origin policy binding %s/%s from RBAC role binding %s", namespace, policyObjectName, name)
+	}
+	return err
+}
+
+func (c *RBACRoleBindingToOriginRoleBindingController) applyOriginRoleBinding(namespace string, roleBinding *authorizationapi.RoleBinding) error {
+	return retryOnConflict(func() error {
+		policyBinding, err := c.policyBindingClient.PolicyBindings(namespace).Get(policyObjectName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			policyBinding = &authorizationapi.PolicyBinding{
+				ObjectMeta:   metav1.ObjectMeta{Name: policyObjectName, Namespace: namespace},
+				RoleBindings: map[string]*authorizationapi.RoleBinding{},
+			}
+			policyBinding.RoleBindings[roleBinding.Name] = roleBinding
+			glog.V(1).Infof("creating origin policy binding %s/%s from RBAC role binding %v", namespace, policyObjectName, roleBinding.Name)
+			_, err := c.policyBindingClient.PolicyBindings(namespace).Create(policyBinding)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if policyBinding.RoleBindings == nil {
+			policyBinding.RoleBindings = map[string]*authorizationapi.RoleBinding{}
+		}
+		policyBinding.RoleBindings[roleBinding.Name] = roleBinding
+
+		glog.V(1).Infof("writing origin role binding %s/%s from RBAC", namespace, roleBinding.Name)
+		_, err = c.policyBindingClient.PolicyBindings(namespace).Update(policyBinding)
+		return err
+	})
+}
+
+func (c *RBACRoleBindingToOriginRoleBindingController) deleteOriginRoleBinding(namespace, name string) error {
+	return retryOnConflict(func() error {
+		policyBinding, err := c.policyBindingClient.PolicyBindings(namespace).Get(policyObjectName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := policyBinding.RoleBindings[name]; !ok {
+			return nil
+		}
+
+		delete(policyBinding.RoleBindings, name)
+		glog.V(1).Infof("removing origin role binding %s/%s, RBAC role binding deleted", namespace, name)
+		_, err = c.policyBindingClient.PolicyBindings(namespace).Update(policyBinding)
+		return err
+	})
+}
+
+func (c *RBACRoleBindingToOriginRoleBindingController) policyBindingEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			originContainerObj := obj.(*authorizationapi.PolicyBinding)
+			if !c.namespaceFilter.Matches(originContainerObj.Namespace) {
+				return
+			}
+			for _, originObj := range originContainerObj.RoleBindings {
+				c.originIndexer.Add(originObj)
+				key, err := controller.KeyFunc(originObj)
+				if err != nil {
+					utilruntime.HandleError(err)
+					continue
+				}
+				c.queue.Add(key)
+			}
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			originContainerObj := cur.(*authorizationapi.PolicyBinding)
+			if !c.namespaceFilter.Matches(originContainerObj.Namespace) {
+				return
+			}
+			for _, originObj := range originContainerObj.RoleBindings {
+				c.originIndexer.Add(originObj)
+				key, err := controller.KeyFunc(originObj)
+				if err != nil {
+					utilruntime.HandleError(err)
+					continue
+				}
+				c.queue.Add(key)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			originContainerObj, ok := obj.(*authorizationapi.PolicyBinding)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					utilruntime.HandleError(fmt.Errorf("Couldn't get object from tombstone %#v", obj))
+				}
+				originContainerObj, ok = tombstone.Obj.(*authorizationapi.PolicyBinding)
+				if !ok {
+					utilruntime.HandleError(fmt.Errorf("Tombstone contained object that is not a runtime.Object %#v", obj))
+				}
+			}
+			if !c.namespaceFilter.Matches(originContainerObj.Namespace) {
+				return
+			}
+
+			for _, originObj := range originContainerObj.RoleBindings {
+				c.originIndexer.Add(originObj)
+				key, err := controller.KeyFunc(originObj)
+				if err != nil {
+					utilruntime.HandleError(err)
+					continue
+				}
+				c.queue.Add(key)
+			}
+		},
+	}
+}